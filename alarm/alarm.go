@@ -0,0 +1,34 @@
+// Package alarm defines the alarm types minions can raise to flag
+// an operational condition that should stop them from silently
+// failing tasks, modeled after etcd's own alarm subsystem.
+package alarm
+
+// Type identifies the kind of condition an alarm represents
+type Type string
+
+const (
+	// NOSPACE is raised when a minion is low on disk space
+	NOSPACE Type = "NOSPACE"
+
+	// CORRUPT is raised when a task's catalog hash no longer
+	// matches the hash recorded for it at submission time
+	CORRUPT Type = "CORRUPT"
+
+	// RESOURCE_FAILURE is raised when a resource in a catalog
+	// fails to converge
+	RESOURCE_FAILURE Type = "RESOURCE_FAILURE"
+
+	// SYSTEMD_UNREACHABLE is raised when a minion repeatedly fails
+	// to reach the systemd D-Bus API
+	SYSTEMD_UNREACHABLE Type = "SYSTEMD_UNREACHABLE"
+)
+
+// Alarm represents an active alarm raised by a minion, along with
+// the resource or task that triggered it
+type Alarm struct {
+	// Type of the alarm
+	Type Type
+
+	// Member is the resource or task ID which raised the alarm
+	Member string
+}
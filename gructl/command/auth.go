@@ -0,0 +1,249 @@
+package command
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/codegangsta/cli"
+	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// tokenCredentials attaches a bearer token to every outgoing RPC,
+// used when gructl authenticates with a JWT loaded from
+// --token-file instead of a username/password pair
+type tokenCredentials struct {
+	token string
+}
+
+// GetRequestMetadata implements the credentials.PerRPCCredentials interface
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": t.token}, nil
+}
+
+// RequireTransportSecurity implements the credentials.PerRPCCredentials
+// interface. It must return true: otherwise grpc would happily send
+// the bearer token over a plaintext connection whenever TLS isn't
+// separately configured, defeating the point of authenticating at all.
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// NewAuthCommands returns the "auth" command and its sub-commands,
+// used to manage etcd users, roles and permissions so that minions
+// can only read/write under their own key prefix
+func NewAuthCommands() cli.Command {
+	return cli.Command{
+		Name:  "auth",
+		Usage: "manage etcd authentication",
+		Subcommands: []cli.Command{
+			{
+				Name:  "user",
+				Usage: "manage etcd users",
+				Subcommands: []cli.Command{
+					{
+						Name:      "add",
+						Usage:     "add a new etcd user",
+						ArgsUsage: "<username>",
+						Action:    execUserAdd,
+					},
+				},
+			},
+			{
+				Name:  "role",
+				Usage: "manage etcd roles",
+				Subcommands: []cli.Command{
+					{
+						Name:      "add",
+						Usage:     "add a new etcd role",
+						ArgsUsage: "<role>",
+						Action:    execRoleAdd,
+					},
+					{
+						Name:      "grant-permission",
+						Usage:     "grant a role read-write access to a key prefix",
+						ArgsUsage: "<role>",
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:  "prefix",
+								Usage: "key prefix the role is granted access to, e.g. /gru/minion/<id>/",
+							},
+						},
+						Action: execRoleGrantPermission,
+					},
+				},
+			},
+		},
+	}
+}
+
+// newEtcdClient creates an etcd v3 client using the connection
+// settings from the global command line flags. It is shared by
+// every gructl subcommand that needs to talk to etcd directly.
+func newEtcdClient(c *cli.Context) (*clientv3.Client, error) {
+	cfg := clientv3.Config{
+		Endpoints:   strings.Split(c.GlobalString("endpoint"), ","),
+		DialTimeout: c.GlobalDuration("timeout"),
+		Username:    c.GlobalString("username"),
+		Password:    c.GlobalString("password"),
+	}
+
+	tlsCert := c.GlobalString("tls-cert")
+	tlsKey := c.GlobalString("tls-key")
+	tlsCA := c.GlobalString("tls-ca")
+	if tlsCert != "" && tlsKey != "" && tlsCA != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+		if err != nil {
+			return nil, err
+		}
+
+		caData, err := ioutil.ReadFile(tlsCA)
+		if err != nil {
+			return nil, err
+		}
+
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("unable to parse CA certificate %s", tlsCA)
+		}
+
+		cfg.TLS = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      caPool,
+		}
+	}
+
+	// A token file takes precedence over username/password, since
+	// it lets gructl authenticate without a long-lived secret
+	// sitting in its shell environment
+	tokenFile := c.GlobalString("token-file")
+	if tokenFile != "" {
+		if cfg.TLS == nil {
+			return nil, fmt.Errorf("--token-file requires TLS (--tls-cert/--tls-key/--tls-ca) to avoid sending the bearer token over a plaintext connection")
+		}
+
+		data, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Username = ""
+		cfg.Password = ""
+		creds := &tokenCredentials{token: strings.TrimSpace(string(data))}
+		cfg.DialOptions = append(cfg.DialOptions, grpc.WithPerRPCCredentials(creds))
+	}
+
+	return clientv3.New(cfg)
+}
+
+// execUserAdd adds a new etcd user, prompting for the password
+// interactively so that it never appears in shell history or
+// /proc/<pid>/cmdline
+func execUserAdd(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 1 {
+		log.Fatal("Usage: gructl auth user add <username>")
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err = client.Auth.UserAdd(ctx, args[0], password)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readPassword prompts for a new password twice without echoing it
+// to the terminal, mirroring etcdctl user add's behavior
+func readPassword() (string, error) {
+	fmt.Print("Password: ")
+	password, err := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Print("Type password again for confirmation: ")
+	confirm, err := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+
+	if string(password) != string(confirm) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+
+	return string(password), nil
+}
+
+// execRoleAdd adds a new etcd role
+func execRoleAdd(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 1 {
+		log.Fatal("Usage: gructl auth role add <role>")
+	}
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err = client.Auth.RoleAdd(ctx, args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// execRoleGrantPermission grants a role read-write access to the
+// given key prefix, so that a minion's role can be scoped to only
+// its own keyspace under EtcdMinionSpace
+func execRoleGrantPermission(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 1 {
+		log.Fatal("Usage: gructl auth role grant-permission <role> --prefix <prefix>")
+	}
+
+	prefix := c.String("prefix")
+	if prefix == "" {
+		log.Fatal("Missing required --prefix flag")
+	}
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err = client.Auth.RoleGrantPermission(ctx, args[0], prefix, clientv3.GetPrefixRangeEnd(prefix), clientv3.PermissionType(clientv3.PermReadWrite))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
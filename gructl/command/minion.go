@@ -0,0 +1,18 @@
+package command
+
+import (
+	"github.com/codegangsta/cli"
+)
+
+// NewMinionCommands returns the "minion" command and its
+// sub-commands
+func NewMinionCommands() cli.Command {
+	return cli.Command{
+		Name:  "minion",
+		Usage: "manage minions",
+		Subcommands: []cli.Command{
+			NewMinionVerifyCommand(),
+			NewMinionAlarmCommand(),
+		},
+	}
+}
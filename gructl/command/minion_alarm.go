@@ -0,0 +1,103 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/dnaeon/gru/alarm"
+	"github.com/dnaeon/gru/minion"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/codegangsta/cli"
+	"golang.org/x/net/context"
+)
+
+// NewMinionAlarmCommand returns the "alarm" sub-command, intended
+// to be registered under the "minion" command tree. It lets
+// operators see and clear the alarms a minion has raised for
+// itself, such as NOSPACE or CORRUPT, once the underlying condition
+// has been remediated.
+func NewMinionAlarmCommand() cli.Command {
+	return cli.Command{
+		Name:  "alarm",
+		Usage: "manage minion alarms",
+		Subcommands: []cli.Command{
+			{
+				Name:      "list",
+				Usage:     "list active alarms for a minion",
+				ArgsUsage: "<minion-id>",
+				Action:    execMinionAlarmList,
+			},
+			{
+				Name:      "disarm",
+				Usage:     "clear an active alarm for a minion",
+				ArgsUsage: "<minion-id> <alarm-type>",
+				Action:    execMinionAlarmDisarm,
+			},
+		},
+	}
+}
+
+// execMinionAlarmList lists the alarms currently active for a minion
+func execMinionAlarmList(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 1 {
+		log.Fatal("Usage: gructl minion alarm list <minion-id>")
+	}
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	alarmDir := filepath.Join(minion.EtcdMinionSpace, args[0], "alarm")
+	resp, err := client.Get(ctx, alarmDir, clientv3.WithPrefix())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		fmt.Println("No active alarms")
+		return
+	}
+
+	for _, kv := range resp.Kvs {
+		fmt.Printf("%s\traised by %s\n", filepath.Base(string(kv.Key)), kv.Value)
+	}
+}
+
+// execMinionAlarmDisarm clears an active alarm for a minion
+func execMinionAlarmDisarm(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 2 {
+		log.Fatal("Usage: gructl minion alarm disarm <minion-id> <alarm-type>")
+	}
+	minionID, alarmType := args[0], args[1]
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	alarmKey := filepath.Join(minion.EtcdMinionSpace, minionID, "alarm", alarmType)
+	resp, err := client.Delete(ctx, alarmKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if resp.Deleted == 0 {
+		log.Fatalf("No active %s alarm found for minion %s\n", alarm.Type(alarmType), minionID)
+	}
+
+	fmt.Printf("Disarmed %s alarm for minion %s\n", alarm.Type(alarmType), minionID)
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/dnaeon/gru/minion"
+	"github.com/dnaeon/gru/task"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/codegangsta/cli"
+	"golang.org/x/net/context"
+)
+
+// NewMinionVerifyCommand returns the "verify" sub-command, intended
+// to be registered under the "minion" command tree. It walks the
+// log of a minion's previously executed tasks and re-hashes each
+// stored task's catalog against the hash recorded for it at
+// submission time. Only the catalog is covered by the hash: a
+// changed Result field of a stored task is not detected.
+func NewMinionVerifyCommand() cli.Command {
+	return cli.Command{
+		Name:      "verify",
+		Usage:     "verify catalog hashes for a minion's task log",
+		ArgsUsage: "<minion-id>",
+		Action:    execMinionVerify,
+	}
+}
+
+// execMinionVerify walks /gru/minion/<id>/log/ and reports any
+// stored task whose catalog no longer matches its catalog hash
+func execMinionVerify(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 1 {
+		log.Fatal("Usage: gructl minion verify <minion-id>")
+	}
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	logDir := filepath.Join(minion.EtcdMinionSpace, args[0], "log")
+	resp, err := client.Get(ctx, logDir, clientv3.WithPrefix())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mismatches := 0
+	for _, kv := range resp.Kvs {
+		t := new(task.Task)
+		if err := json.Unmarshal(kv.Value, t); err != nil {
+			log.Printf("Skipping invalid task at %s: %s\n", kv.Key, err)
+			continue
+		}
+
+		ok, err := minion.VerifyCatalogHash(t)
+		if err != nil {
+			log.Printf("Failed to verify task %s: %s\n", t.TaskID, err)
+			continue
+		}
+
+		if !ok {
+			mismatches++
+			fmt.Printf("MISMATCH: task %s at %s\n", t.TaskID, kv.Key)
+		}
+	}
+
+	fmt.Printf("Checked %d tasks, %d mismatches\n", len(resp.Kvs), mismatches)
+}
@@ -0,0 +1,194 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/dnaeon/gru/minion"
+	"github.com/dnaeon/gru/task"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/codegangsta/cli"
+	"github.com/pborman/uuid"
+	"golang.org/x/net/context"
+)
+
+// NewTaskCommands returns the "task" command and its sub-commands,
+// used to manage the dead-letter queue a minion moves tasks into
+// once they exceed their maximum number of processing attempts
+func NewTaskCommands() cli.Command {
+	return cli.Command{
+		Name:  "task",
+		Usage: "manage minion tasks",
+		Subcommands: []cli.Command{
+			{
+				Name:      "submit",
+				Usage:     "submit a task to a minion's queue",
+				ArgsUsage: "<minion-id> <task-file>",
+				Action:    execTaskSubmit,
+			},
+			{
+				Name:      "requeue",
+				Usage:     "requeue a dead-lettered task for another attempt",
+				ArgsUsage: "<minion-id> <task-id>",
+				Action:    execTaskRequeue,
+			},
+			{
+				Name:  "deadletter",
+				Usage: "manage dead-lettered tasks",
+				Subcommands: []cli.Command{
+					{
+						Name:      "ls",
+						Usage:     "list dead-lettered tasks for a minion",
+						ArgsUsage: "<minion-id>",
+						Action:    execTaskDeadletterList,
+					},
+				},
+			},
+		},
+	}
+}
+
+// execTaskSubmit reads a task definition from a JSON file, stamps
+// it with the SHA-256 hash of its catalog so the minion can detect
+// transport corruption or tampering, and places it on a minion's
+// queue
+func execTaskSubmit(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 2 {
+		log.Fatal("Usage: gructl task submit <minion-id> <task-file>")
+	}
+	minionID, taskFile := args[0], args[1]
+
+	data, err := ioutil.ReadFile(taskFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t := new(task.Task)
+	if err := json.Unmarshal(data, t); err != nil {
+		log.Fatal(err)
+	}
+
+	if t.TaskID == nil {
+		t.TaskID = uuid.NewRandom()
+	}
+
+	hash, err := minion.HashCatalog(t)
+	if err != nil {
+		log.Fatal(err)
+	}
+	t.CatalogHash = hash
+
+	payload, err := json.Marshal(t)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	queueKey := filepath.Join(minion.EtcdMinionSpace, minionID, "queue", t.TaskID.String())
+	if _, err := client.Put(ctx, queueKey, string(payload)); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Task %s submitted to minion %s\n", t.TaskID, minionID)
+}
+
+// execTaskRequeue moves a task out of a minion's dead-letter
+// directory and back onto its queue, resetting the attempt count
+func execTaskRequeue(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 2 {
+		log.Fatal("Usage: gructl task requeue <minion-id> <task-id>")
+	}
+	minionID, taskID := args[0], args[1]
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deadletterKey := filepath.Join(minion.EtcdMinionSpace, minionID, "deadletter", taskID)
+	resp, err := client.Get(ctx, deadletterKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		log.Fatalf("No dead-lettered task %s found for minion %s\n", taskID, minionID)
+	}
+
+	t := new(task.Task)
+	if err := json.Unmarshal(resp.Kvs[0].Value, t); err != nil {
+		log.Fatal(err)
+	}
+
+	t.Attempts = 0
+	t.LastError = ""
+	data, err := json.Marshal(t)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	queueKey := filepath.Join(minion.EtcdMinionSpace, minionID, "queue", taskID)
+	_, err = client.Txn(ctx).
+		Then(
+			clientv3.OpPut(queueKey, string(data)),
+			clientv3.OpDelete(deadletterKey),
+		).
+		Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Task %s requeued for minion %s\n", taskID, minionID)
+}
+
+// execTaskDeadletterList lists the tasks in a minion's dead-letter directory
+func execTaskDeadletterList(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 1 {
+		log.Fatal("Usage: gructl task deadletter ls <minion-id>")
+	}
+
+	client, err := newEtcdClient(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deadletterDir := filepath.Join(minion.EtcdMinionSpace, args[0], "deadletter")
+	resp, err := client.Get(ctx, deadletterDir, clientv3.WithPrefix())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, kv := range resp.Kvs {
+		t := new(task.Task)
+		if err := json.Unmarshal(kv.Value, t); err != nil {
+			log.Printf("Skipping invalid task at %s: %s\n", kv.Key, err)
+			continue
+		}
+
+		fmt.Printf("%s\tattempts=%d\tlast_error=%s\n", t.TaskID, t.Attempts, t.LastError)
+	}
+}
@@ -18,7 +18,7 @@ func main() {
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name: "endpoint",
-			Value: "http://127.0.0.1:2379,http://localhost:4001",
+			Value: "http://127.0.0.1:2379",
 			Usage: "etcd cluster endpoints",
 			EnvVar: "GRUCTL_ENDPOINT",
 		},
@@ -34,6 +34,30 @@ func main() {
 			Usage: "password to use for authentication",
 			EnvVar: "GRUCTL_PASSWORD",
 		},
+		cli.StringFlag{
+			Name: "token-file",
+			Value: "",
+			Usage: "file containing a JWT bearer token to use for authentication, takes precedence over username/password",
+			EnvVar: "GRUCTL_TOKEN_FILE",
+		},
+		cli.StringFlag{
+			Name: "tls-cert",
+			Value: "",
+			Usage: "path to the client TLS certificate",
+			EnvVar: "GRUCTL_TLS_CERT",
+		},
+		cli.StringFlag{
+			Name: "tls-key",
+			Value: "",
+			Usage: "path to the client TLS key",
+			EnvVar: "GRUCTL_TLS_KEY",
+		},
+		cli.StringFlag{
+			Name: "tls-ca",
+			Value: "",
+			Usage: "path to the CA certificate used to verify the etcd server",
+			EnvVar: "GRUCTL_TLS_CA",
+		},
 		cli.DurationFlag{
 			Name: "timeout",
 			Value: time.Second,
@@ -44,6 +68,8 @@ func main() {
 
 	app.Commands = []cli.Command{
 		command.NewMinionCommands(),
+		command.NewAuthCommands(),
+		command.NewTaskCommands(),
 	}
 
 	app.Run(os.Args)
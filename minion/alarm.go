@@ -0,0 +1,27 @@
+package minion
+
+import (
+	"github.com/dnaeon/gru/alarm"
+)
+
+// AlarmStore persists a minion's active alarms in etcd, modeled
+// after etcd's own alarm store: once an alarm of a given type is
+// raised it stays active under /gru/minion/<id>/alarm/<type> until
+// explicitly disarmed. TaskRunner consults it before dispatching a
+// task, so that a minion under a NOSPACE or CORRUPT alarm holds
+// its queue instead of silently failing every task it runs.
+type AlarmStore interface {
+	// Raise activates an alarm, recording the resource or task
+	// which triggered it
+	Raise(t alarm.Type, member string) error
+
+	// Disarm clears an active alarm
+	Disarm(t alarm.Type) error
+
+	// Active reports whether an alarm of the given type is
+	// currently raised
+	Active(t alarm.Type) (bool, error)
+
+	// List returns all currently active alarms
+	List() ([]alarm.Alarm, error)
+}
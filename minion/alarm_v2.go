@@ -0,0 +1,87 @@
+package minion
+
+import (
+	"path/filepath"
+
+	"github.com/dnaeon/gru/alarm"
+
+	etcdclient "github.com/coreos/etcd/client"
+	"golang.org/x/net/context"
+)
+
+// v2AlarmStore is an AlarmStore backed by the etcd v2 KeysAPI
+type v2AlarmStore struct {
+	kapi     etcdclient.KeysAPI
+	alarmDir string
+}
+
+// newV2AlarmStore creates an AlarmStore for a v2-backed minion
+func newV2AlarmStore(kapi etcdclient.KeysAPI, rootDir string) AlarmStore {
+	return &v2AlarmStore{
+		kapi:     kapi,
+		alarmDir: filepath.Join(rootDir, "alarm"),
+	}
+}
+
+func (a *v2AlarmStore) key(t alarm.Type) string {
+	return filepath.Join(a.alarmDir, string(t))
+}
+
+// Raise activates an alarm, recording the resource or task which
+// triggered it
+func (a *v2AlarmStore) Raise(t alarm.Type, member string) error {
+	opts := &etcdclient.SetOptions{
+		PrevExist: etcdclient.PrevIgnore,
+	}
+
+	_, err := a.kapi.Set(context.Background(), a.key(t), member, opts)
+
+	return err
+}
+
+// Disarm clears an active alarm
+func (a *v2AlarmStore) Disarm(t alarm.Type) error {
+	_, err := a.kapi.Delete(context.Background(), a.key(t), nil)
+
+	return err
+}
+
+// Active reports whether an alarm of the given type is currently raised
+func (a *v2AlarmStore) Active(t alarm.Type) (bool, error) {
+	_, err := a.kapi.Get(context.Background(), a.key(t), nil)
+	if err != nil {
+		if eerr, ok := err.(etcdclient.Error); ok && eerr.Code == etcdclient.ErrorCodeKeyNotFound {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// List returns all currently active alarms
+func (a *v2AlarmStore) List() ([]alarm.Alarm, error) {
+	opts := &etcdclient.GetOptions{
+		Recursive: true,
+	}
+
+	resp, err := a.kapi.Get(context.Background(), a.alarmDir, opts)
+	if err != nil {
+		if eerr, ok := err.(etcdclient.Error); ok && eerr.Code == etcdclient.ErrorCodeKeyNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	alarms := make([]alarm.Alarm, 0, len(resp.Node.Nodes))
+	for _, node := range resp.Node.Nodes {
+		alarms = append(alarms, alarm.Alarm{
+			Type:   alarm.Type(filepath.Base(node.Key)),
+			Member: node.Value,
+		})
+	}
+
+	return alarms, nil
+}
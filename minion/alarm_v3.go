@@ -0,0 +1,74 @@
+package minion
+
+import (
+	"path/filepath"
+
+	"github.com/dnaeon/gru/alarm"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/net/context"
+)
+
+// v3AlarmStore is an AlarmStore backed by the etcd v3 client.
+// Unlike the minion's own registration keys, alarms are not tied
+// to a lease: they must outlive the minion that raised them so an
+// operator can see and clear them after it is restarted or repaired.
+type v3AlarmStore struct {
+	client   *clientv3.Client
+	alarmDir string
+}
+
+// newV3AlarmStore creates an AlarmStore for a v3-backed minion
+func newV3AlarmStore(client *clientv3.Client, rootDir string) AlarmStore {
+	return &v3AlarmStore{
+		client:   client,
+		alarmDir: filepath.Join(rootDir, "alarm"),
+	}
+}
+
+func (a *v3AlarmStore) key(t alarm.Type) string {
+	return filepath.Join(a.alarmDir, string(t))
+}
+
+// Raise activates an alarm, recording the resource or task which
+// triggered it
+func (a *v3AlarmStore) Raise(t alarm.Type, member string) error {
+	_, err := a.client.Put(context.Background(), a.key(t), member)
+
+	return err
+}
+
+// Disarm clears an active alarm
+func (a *v3AlarmStore) Disarm(t alarm.Type) error {
+	_, err := a.client.Delete(context.Background(), a.key(t))
+
+	return err
+}
+
+// Active reports whether an alarm of the given type is currently raised
+func (a *v3AlarmStore) Active(t alarm.Type) (bool, error) {
+	resp, err := a.client.Get(context.Background(), a.key(t))
+	if err != nil {
+		return false, err
+	}
+
+	return len(resp.Kvs) > 0, nil
+}
+
+// List returns all currently active alarms
+func (a *v3AlarmStore) List() ([]alarm.Alarm, error) {
+	resp, err := a.client.Get(context.Background(), a.alarmDir, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	alarms := make([]alarm.Alarm, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		alarms = append(alarms, alarm.Alarm{
+			Type:   alarm.Type(filepath.Base(string(kv.Key))),
+			Member: string(kv.Value),
+		})
+	}
+
+	return alarms, nil
+}
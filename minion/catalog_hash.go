@@ -0,0 +1,42 @@
+package minion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/dnaeon/gru/task"
+)
+
+// HashCatalog computes the SHA-256 hash of a task's serialized
+// catalog. gructl computes and stores the same hash alongside the
+// task when it is submitted (see "gructl task submit"), so that a
+// minion can detect transport corruption or tampering before
+// executing the catalog.
+func HashCatalog(t *task.Task) (string, error) {
+	data, err := json.Marshal(t.Catalog)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyCatalogHash reports whether the task's catalog still
+// matches the hash recorded for it at submission time. A task
+// submitted without a hash (e.g. by an older gructl) is not
+// considered corrupt.
+func VerifyCatalogHash(t *task.Task) (bool, error) {
+	if t.CatalogHash == "" {
+		return true, nil
+	}
+
+	sum, err := HashCatalog(t)
+	if err != nil {
+		return false, err
+	}
+
+	return sum == t.CatalogHash, nil
+}
@@ -0,0 +1,96 @@
+package minion
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	etcdclient "github.com/coreos/etcd/client"
+)
+
+// EtcdMinionConfig holds the settings used for creating a new
+// etcd-backed minion
+type EtcdMinionConfig struct {
+	// Name of the minion
+	Name string
+
+	// EtcdConfig is the configuration used for the underlying
+	// etcd v2 client
+	EtcdConfig etcdclient.Config
+
+	// Username and Password are used for authenticating against
+	// etcd when TokenFile is not set
+	Username string
+	Password string
+
+	// TokenFile contains a JWT bearer token used for
+	// authenticating against etcd instead of Username/Password
+	TokenFile string
+
+	// TLSCert, TLSKey and TLSCA configure mTLS between the minion
+	// and etcd. All three must be set in order to enable it.
+	TLSCert string
+	TLSKey  string
+	TLSCA   string
+}
+
+// tokenRoundTripper attaches a bearer token to every outgoing
+// request, mirroring the way etcd's own clients authenticate
+// requests signed with a JWT
+type tokenRoundTripper struct {
+	token     string
+	transport http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *tokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", t.token)
+
+	return t.transport.RoundTrip(req)
+}
+
+// newTransport builds the HTTP transport used by the etcd client,
+// wiring in mTLS and/or a JWT bearer token when configured
+func (c *EtcdMinionConfig) newTransport() (http.RoundTripper, error) {
+	transport := etcdclient.DefaultTransport
+
+	if c.TLSCert != "" && c.TLSKey != "" && c.TLSCA != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+
+		caData, err := ioutil.ReadFile(c.TLSCA)
+		if err != nil {
+			return nil, err
+		}
+
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("unable to parse CA certificate %s", c.TLSCA)
+		}
+
+		tlsConfig := &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      caPool,
+		}
+		transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	if c.TokenFile != "" {
+		data, err := ioutil.ReadFile(c.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		transport = &tokenRoundTripper{
+			token:     strings.TrimSpace(string(data)),
+			transport: transport,
+		}
+	}
+
+	return transport, nil
+}
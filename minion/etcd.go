@@ -12,7 +12,9 @@ import (
 	"time"
 
 	"github.com/dnaeon/backoff"
+	"github.com/dnaeon/gru/alarm"
 	"github.com/dnaeon/gru/classifier"
+	"github.com/dnaeon/gru/resource"
 	"github.com/dnaeon/gru/task"
 	"github.com/dnaeon/gru/utils"
 
@@ -47,6 +49,9 @@ type etcdMinion struct {
 	// KeysAPI client to etcd
 	kapi etcdclient.KeysAPI
 
+	// Store for active minion alarms
+	alarms AlarmStore
+
 	// Channel over which tasks are sent for processing
 	taskQueue chan *task.Task
 
@@ -55,14 +60,30 @@ type etcdMinion struct {
 }
 
 // NewEtcdMinion creates a new minion with etcd backend
-func NewEtcdMinion(name string, cfg etcdclient.Config) Minion {
-	c, err := etcdclient.New(cfg)
+func NewEtcdMinion(cfg *EtcdMinionConfig) (Minion, error) {
+	etcdCfg := cfg.EtcdConfig
+
+	// A token file takes precedence over username/password, since
+	// it lets a minion authenticate without a long-lived secret
+	// sitting in its config
+	if cfg.TokenFile == "" {
+		etcdCfg.Username = cfg.Username
+		etcdCfg.Password = cfg.Password
+	}
+
+	transport, err := cfg.newTransport()
+	if err != nil {
+		return nil, err
+	}
+	etcdCfg.Transport = transport
+
+	c, err := etcdclient.New(etcdCfg)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	kapi := etcdclient.NewKeysAPI(c)
-	id := utils.GenerateUUID(name)
+	id := utils.GenerateUUID(cfg.Name)
 	rootDir := filepath.Join(EtcdMinionSpace, id.String())
 	queueDir := filepath.Join(rootDir, "queue")
 	classifierDir := filepath.Join(rootDir, "classifier")
@@ -71,18 +92,19 @@ func NewEtcdMinion(name string, cfg etcdclient.Config) Minion {
 	done := make(chan struct{})
 
 	m := &etcdMinion{
-		name:          name,
+		name:          cfg.Name,
 		rootDir:       rootDir,
 		queueDir:      queueDir,
 		classifierDir: classifierDir,
 		logDir:        logDir,
 		id:            id,
 		kapi:          kapi,
+		alarms:        newV2AlarmStore(kapi, rootDir),
 		taskQueue:     taskQueue,
 		done:          done,
 	}
 
-	return m
+	return m, nil
 }
 
 // Checks for any pending tasks and sends them
@@ -152,9 +174,19 @@ func (m *etcdMinion) processTask(t *task.Task) error {
 
 	log.Printf("Processing task %s\n", t.TaskID)
 
+	ok, err := VerifyCatalogHash(t)
+	if err != nil || !ok {
+		log.Printf("Refusing to run corrupt task %s\n", t.TaskID)
+		t.State = task.TaskStateCorrupt
+		m.SaveTaskResult(t)
+		m.raiseCorruptionAlarm(t)
+
+		return fmt.Errorf("catalog hash mismatch for task %s", t.TaskID)
+	}
+
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "Loaded %d resources from catalog", t.Catalog.Len())
-	err := t.Catalog.Run(&buf)
+	err = t.Catalog.Run(&buf)
 	t.TimeProcessed = time.Now().Unix()
 	t.Result = buf.String()
 
@@ -171,6 +203,52 @@ func (m *etcdMinion) processTask(t *task.Task) error {
 	return err
 }
 
+// raiseCorruptionAlarm records a hash mismatch for a task so that
+// operators can spot tampering or transport corruption
+func (m *etcdMinion) raiseCorruptionAlarm(t *task.Task) {
+	if err := m.alarms.Raise(alarm.CORRUPT, t.TaskID.String()); err != nil {
+		log.Printf("Failed to raise corruption alarm for task %s: %s\n", t.TaskID, err)
+	}
+}
+
+// requeueTask puts a task back onto the queue, e.g. when task
+// processing is held because of an active NOSPACE or CORRUPT alarm
+func (m *etcdMinion) requeueTask(t *task.Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	key := filepath.Join(m.queueDir, t.TaskID.String())
+	opts := &etcdclient.SetOptions{
+		PrevExist: etcdclient.PrevIgnore,
+	}
+
+	_, err = m.kapi.Set(context.Background(), key, string(data), opts)
+
+	return err
+}
+
+// holdForAlarm reports whether a NOSPACE or CORRUPT alarm is
+// currently active for the minion, holding the task in queue and
+// logging rather than dispatching it to processTask, mirroring how
+// etcd itself rejects writes while an alarm is active
+func (m *etcdMinion) holdForAlarm(t *task.Task) (bool, error) {
+	for _, at := range []alarm.Type{alarm.NOSPACE, alarm.CORRUPT} {
+		active, err := m.alarms.Active(at)
+		if err != nil {
+			return false, err
+		}
+
+		if active {
+			log.Printf("Holding task %s in queue: %s alarm is active\n", t.TaskID, at)
+			return true, m.requeueTask(t)
+		}
+	}
+
+	return false, nil
+}
+
 // Classifies the minion
 func (m *etcdMinion) classify() error {
 	for key := range classifier.Registry {
@@ -320,6 +398,14 @@ func (m *etcdMinion) TaskRunner(c <-chan *task.Task) error {
 		case <-m.done:
 			break
 		case t := <-c:
+			held, err := m.holdForAlarm(t)
+			if err != nil {
+				log.Printf("Failed to check alarms for task %s: %s\n", t.TaskID, err)
+			}
+			if held {
+				continue
+			}
+
 			t.State = task.TaskStateQueued
 			t.TimeReceived = time.Now().Unix()
 			m.SaveTaskResult(t)
@@ -359,6 +445,12 @@ func (m *etcdMinion) SaveTaskResult(t *task.Task) error {
 
 // Seve starts the minion
 func (m *etcdMinion) Serve() error {
+	resource.AlarmRaiser = func(t alarm.Type, member string) {
+		if err := m.alarms.Raise(t, member); err != nil {
+			log.Printf("Failed to raise %s alarm: %s\n", t, err)
+		}
+	}
+
 	err := m.SetName(m.name)
 	if err != nil {
 		return err
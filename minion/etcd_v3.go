@@ -0,0 +1,775 @@
+package minion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dnaeon/backoff"
+	"github.com/dnaeon/gru/alarm"
+	"github.com/dnaeon/gru/classifier"
+	"github.com/dnaeon/gru/resource"
+	"github.com/dnaeon/gru/task"
+	"github.com/dnaeon/gru/utils"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/pborman/uuid"
+	"golang.org/x/net/context"
+)
+
+// minionLeaseTTL is the TTL in seconds of the lease a minion grants
+// for its registration keys. As long as the minion is alive and
+// keeping the lease refreshed, its name/lastseen/classifier keys
+// remain visible in etcd. Once it stops or crashes, the lease
+// expires and the minion naturally disappears from the keyspace.
+const minionLeaseTTL = 60
+
+// taskProcessingLeaseTTL is the TTL in seconds of the lease a task
+// is claimed under. As long as the minion processing the task is
+// alive the lease is kept refreshed; if the minion dies the lease
+// expires, the task's processing key disappears, and the reaper
+// re-queues it.
+const taskProcessingLeaseTTL = 300
+
+// taskMaxAttempts is the number of times a task may be reclaimed
+// from the queue before it is moved to the dead-letter directory
+const taskMaxAttempts = 5
+
+// v3EtcdMinion is a Minion which uses the etcd v3 API instead of
+// the legacy v2 KeysAPI used by etcdMinion. Registration keys are
+// tied to a lease instead of a TTL on every individual Set call,
+// and tasks are claimed from the queue with a transaction instead
+// of a plain Delete, so that two minions racing on the same queue
+// entry cannot both pick it up.
+type v3EtcdMinion struct {
+	// Name of the minion
+	name string
+
+	// Minion root directory in etcd
+	rootDir string
+
+	// Minion queue directory in etcd
+	queueDir string
+
+	// Directory holding tasks currently claimed and being
+	// processed by this minion
+	processingDir string
+
+	// Directory holding tasks which reached taskMaxAttempts
+	deadletterDir string
+
+	// Log directory of previously executed tasks
+	logDir string
+
+	// Classifier directory in etcd
+	classifierDir string
+
+	// Minion unique identifier
+	id uuid.UUID
+
+	// etcd v3 client
+	client *clientv3.Client
+
+	// Store for active minion alarms
+	alarms AlarmStore
+
+	// Lease kept alive for the lifetime of the minion, used for
+	// the minion's own liveness keys
+	leaseID clientv3.LeaseID
+
+	// taskLeases tracks the lease (and the cancel func for the
+	// goroutine keeping it alive) each in-flight task was claimed
+	// under, keyed by task ID, so both can be torn down once the
+	// task reaches a terminal state
+	taskLeases sync.Map
+
+	// releasing tracks, by task ID, processing keys whose removal
+	// was deliberately triggered by releaseTaskLease rather than by
+	// the owning minion crashing. The value stored under a
+	// processing key is the pre-run payload from the queue and is
+	// never refreshed, so the reaper can't tell the two cases apart
+	// from the deleted key's contents alone; it consults this map
+	// instead, and reclaimOrphan consumes the marker once it has
+	// been checked.
+	releasing sync.Map
+
+	// Channel over which tasks are sent for processing
+	taskQueue chan *task.Task
+
+	// Channel used to signal shutdown time
+	done chan struct{}
+}
+
+// NewV3EtcdMinion creates a new minion which talks to etcd using
+// the v3 client API
+func NewV3EtcdMinion(cfg *V3EtcdMinionConfig) (Minion, error) {
+	clientCfg, err := cfg.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := clientv3.New(clientCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	id := utils.GenerateUUID(cfg.Name)
+	rootDir := filepath.Join(EtcdMinionSpace, id.String())
+	queueDir := filepath.Join(rootDir, "queue")
+	processingDir := filepath.Join(rootDir, "processing")
+	deadletterDir := filepath.Join(rootDir, "deadletter")
+	classifierDir := filepath.Join(rootDir, "classifier")
+	logDir := filepath.Join(rootDir, "log")
+
+	m := &v3EtcdMinion{
+		name:          cfg.Name,
+		rootDir:       rootDir,
+		queueDir:      queueDir,
+		processingDir: processingDir,
+		deadletterDir: deadletterDir,
+		classifierDir: classifierDir,
+		logDir:        logDir,
+		id:            id,
+		client:        client,
+		alarms:        newV3AlarmStore(client, rootDir),
+		taskQueue:     make(chan *task.Task),
+		done:          make(chan struct{}),
+	}
+
+	return m, nil
+}
+
+// grantLease acquires a lease for the minion's liveness keys and
+// starts a goroutine which keeps it alive for as long as the
+// minion is running
+func (m *v3EtcdMinion) grantLease() error {
+	resp, err := m.client.Grant(context.Background(), minionLeaseTTL)
+	if err != nil {
+		return err
+	}
+
+	m.leaseID = resp.ID
+	keepAlive, err := m.client.KeepAlive(context.Background(), m.leaseID)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-m.done:
+				return
+			case _, ok := <-keepAlive:
+				if !ok {
+					log.Println("Lease keep-alive channel closed")
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// taskLease tracks the lease a claimed task is processing under,
+// along with the cancel func that stops the goroutine keeping it
+// alive once the task reaches a terminal state
+type taskLease struct {
+	id     clientv3.LeaseID
+	cancel context.CancelFunc
+}
+
+// keepAliveTaskLease refreshes a task's processing lease for as
+// long as ctx is not cancelled, mirroring grantLease's keep-alive
+// for the minion's own registration lease. Without this, any task
+// whose Catalog.Run takes longer than taskProcessingLeaseTTL would
+// have its processing key expire while still running, and the
+// reaper would re-queue and re-execute it concurrently with itself.
+func (m *v3EtcdMinion) keepAliveTaskLease(ctx context.Context, leaseID clientv3.LeaseID) error {
+	keepAlive, err := m.client.KeepAlive(ctx, leaseID)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-keepAlive:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// ID returns the minion unique identifier
+func (m *v3EtcdMinion) ID() uuid.UUID {
+	return m.id
+}
+
+// SetName sets the human-readable name of the minion in etcd
+func (m *v3EtcdMinion) SetName(name string) error {
+	nameKey := filepath.Join(m.rootDir, "name")
+
+	_, err := m.client.Put(context.Background(), nameKey, name, clientv3.WithLease(m.leaseID))
+	if err != nil {
+		log.Printf("Failed to set name of minion: %s\n", err)
+	}
+
+	return err
+}
+
+// SetLastseen sets the time the minion was last seen in
+// seconds since the Epoch
+func (m *v3EtcdMinion) SetLastseen(s int64) error {
+	lastseenKey := filepath.Join(m.rootDir, "lastseen")
+	lastseenValue := strconv.FormatInt(s, 10)
+
+	_, err := m.client.Put(context.Background(), lastseenKey, lastseenValue, clientv3.WithLease(m.leaseID))
+	if err != nil {
+		log.Printf("Failed to set lastseen time: %s\n", err)
+	}
+
+	return err
+}
+
+// SetClassifier sets a classifier for the minion in etcd
+func (m *v3EtcdMinion) SetClassifier(c *classifier.Classifier) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		log.Printf("Failed to serialize classifier %s: %s\n", c.Key, err)
+		return err
+	}
+
+	klassifierKey := filepath.Join(m.classifierDir, c.Key)
+	_, err = m.client.Put(context.Background(), klassifierKey, string(data), clientv3.WithLease(m.leaseID))
+	if err != nil {
+		log.Printf("Failed to set classifier %s: %s\n", c.Key, err)
+	}
+
+	return err
+}
+
+// classify refreshes the classifiers registered for the minion
+func (m *v3EtcdMinion) classify() error {
+	for key := range classifier.Registry {
+		klassifier, err := classifier.Get(key)
+		if err != nil {
+			log.Printf("Failed to get classifier %s: %s\n", key, err)
+			continue
+		}
+
+		m.SetClassifier(klassifier)
+	}
+
+	return nil
+}
+
+// claimTask attempts to move a task from the queue to the
+// processing directory under a short-lived lease, failing the
+// transaction if another minion has already claimed it since the
+// key was last observed at the given revision. As long as the
+// lease is kept alive the processing key survives; if the minion
+// dies while holding it, the lease expires, the key disappears,
+// and the reaper re-queues the task.
+func (m *v3EtcdMinion) claimTask(key string, rev int64, value []byte) (*clientv3.TxnResponse, clientv3.LeaseID, error) {
+	lease, err := m.client.Grant(context.Background(), taskProcessingLeaseTTL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	processingKey := filepath.Join(m.processingDir, filepath.Base(key))
+	txnResp, err := m.client.Txn(context.Background()).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).
+		Then(
+			clientv3.OpPut(processingKey, string(value), clientv3.WithLease(lease.ID)),
+			clientv3.OpDelete(key),
+		).
+		Commit()
+
+	if err != nil || !txnResp.Succeeded {
+		m.client.Revoke(context.Background(), lease.ID)
+		return txnResp, 0, err
+	}
+
+	return txnResp, lease.ID, nil
+}
+
+// dispatch unmarshals a claimed task, keeps the lease it was
+// claimed under alive for as long as the task is in flight, and
+// hands it to the task queue for processing
+func (m *v3EtcdMinion) dispatch(data []byte, leaseID clientv3.LeaseID) {
+	t := new(task.Task)
+	if err := json.Unmarshal(data, t); err != nil {
+		log.Printf("Received invalid task: %s\n", err)
+		m.client.Revoke(context.Background(), leaseID)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := m.keepAliveTaskLease(ctx, leaseID); err != nil {
+		log.Printf("Failed to keep processing lease alive for task %s: %s\n", t.TaskID, err)
+		cancel()
+		m.client.Revoke(context.Background(), leaseID)
+		return
+	}
+
+	m.taskLeases.Store(t.TaskID.String(), &taskLease{id: leaseID, cancel: cancel})
+	m.taskQueue <- t
+}
+
+// checkQueue lists any pending tasks in the queue and sends them
+// for processing if there are any
+func (m *v3EtcdMinion) checkQueue() error {
+	resp, err := m.client.Get(context.Background(), m.queueDir, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil
+	}
+
+	log.Printf("Found %d pending tasks in queue", len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		txnResp, leaseID, err := m.claimTask(string(kv.Key), kv.ModRevision, kv.Value)
+		if err != nil {
+			log.Printf("Failed to claim task %s: %s\n", kv.Key, err)
+			continue
+		}
+
+		if !txnResp.Succeeded {
+			// Another minion (or checkQueue running concurrently)
+			// already claimed this task
+			continue
+		}
+
+		m.dispatch(kv.Value, leaseID)
+	}
+
+	return nil
+}
+
+// requeueOrphans re-queues any tasks still sitting in the
+// processing directory from a previous run of this minion that
+// crashed before its task leases expired
+func (m *v3EtcdMinion) requeueOrphans() error {
+	resp, err := m.client.Get(context.Background(), m.processingDir, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range resp.Kvs {
+		m.reclaimOrphan(kv.Value)
+		m.client.Delete(context.Background(), string(kv.Key))
+	}
+
+	return nil
+}
+
+// reaper watches the processing directory for keys that vanish
+// without releaseTaskLease having deliberately removed them, which
+// means the owning minion died while the task's lease was still
+// held
+func (m *v3EtcdMinion) reaper() {
+	watchCh := m.client.Watch(context.Background(), m.processingDir, clientv3.WithPrefix(), clientv3.WithPrevKV())
+
+	for resp := range watchCh {
+		for _, ev := range resp.Events {
+			if ev.Type != clientv3.EventTypeDelete || ev.PrevKv == nil {
+				continue
+			}
+
+			m.reclaimOrphan(ev.PrevKv.Value)
+		}
+	}
+}
+
+// isTerminalState reports whether a task has already reached a
+// terminal state. The processing key's stored value is the
+// pre-run payload from the queue and is never refreshed, so in
+// practice this only ever sees a queued/processing state; it is
+// kept as a defensive fallback alongside the releasing map in
+// reclaimOrphan rather than relied on by itself
+func isTerminalState(t *task.Task) bool {
+	switch t.State {
+	case task.TaskStateSuccess, task.TaskStateFailed, task.TaskStateCorrupt:
+		return true
+	default:
+		return false
+	}
+}
+
+// markReleasing records that the processing key for the given task
+// ID is about to be removed deliberately, so that a consumeReleasing
+// call from the reaper can recognize the resulting delete event and
+// skip reclaiming the task
+func markReleasing(releasing *sync.Map, taskID string) {
+	releasing.Store(taskID, struct{}{})
+}
+
+// consumeReleasing reports whether the processing key for the given
+// task ID was just removed by markReleasing rather than by a lease
+// expiring out from under a crashed minion, consuming the marker in
+// the process so it is only ever honored once
+func consumeReleasing(releasing *sync.Map, taskID string) bool {
+	if _, ok := releasing.Load(taskID); ok {
+		releasing.Delete(taskID)
+		return true
+	}
+
+	return false
+}
+
+// prepareReclaim records another failed attempt on an orphaned
+// task and reports whether it has exhausted taskMaxAttempts and
+// should be moved to the dead-letter directory instead of re-queued
+func prepareReclaim(t *task.Task) bool {
+	t.Attempts++
+	t.LastError = fmt.Sprintf("minion died while processing task (attempt %d)", t.Attempts)
+
+	return t.Attempts >= taskMaxAttempts
+}
+
+// reclaimOrphan inspects a task that disappeared from the
+// processing directory and, if its removal wasn't a deliberate
+// release by this same minion, either re-queues it with an
+// incremented attempt count or moves it to the dead-letter
+// directory once taskMaxAttempts is reached
+func (m *v3EtcdMinion) reclaimOrphan(data []byte) {
+	t := new(task.Task)
+	if err := json.Unmarshal(data, t); err != nil {
+		log.Printf("Failed to unmarshal orphaned task: %s\n", err)
+		return
+	}
+
+	if consumeReleasing(&m.releasing, t.TaskID.String()) {
+		return
+	}
+
+	if isTerminalState(t) {
+		return
+	}
+
+	if prepareReclaim(t) {
+		m.deadletterTask(t)
+		return
+	}
+
+	log.Printf("Re-queuing orphaned task %s (attempt %d)\n", t.TaskID, t.Attempts)
+	m.requeueTask(t)
+}
+
+// requeueTask puts a task back onto the queue for another attempt
+func (m *v3EtcdMinion) requeueTask(t *task.Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	key := filepath.Join(m.queueDir, t.TaskID.String())
+	_, err = m.client.Put(context.Background(), key, string(data))
+	if err != nil {
+		log.Printf("Failed to requeue task %s: %s\n", t.TaskID, err)
+	}
+
+	return err
+}
+
+// deadletterTask moves a task which exhausted its attempts to the
+// dead-letter directory, along with the error from its last attempt
+func (m *v3EtcdMinion) deadletterTask(t *task.Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	key := filepath.Join(m.deadletterDir, t.TaskID.String())
+	_, err = m.client.Put(context.Background(), key, string(data))
+	if err != nil {
+		log.Printf("Failed to move task %s to dead-letter: %s\n", t.TaskID, err)
+		return err
+	}
+
+	log.Printf("Task %s moved to dead-letter after %d attempts\n", t.TaskID, t.Attempts)
+
+	return nil
+}
+
+// periodicRunner runs periodic jobs such as refreshing classifiers
+// and updating the lastseen time every five minutes
+func (m *v3EtcdMinion) periodicRunner() {
+	schedule := time.Minute * 5
+	ticker := time.NewTicker(schedule)
+	log.Printf("Periodic scheduler set to run every %s\n", schedule)
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.classify()
+			m.checkQueue()
+			m.SetLastseen(now.Unix())
+		}
+	}
+}
+
+// raiseCorruptionAlarm records a hash mismatch for a task so that
+// operators can spot tampering or transport corruption
+func (m *v3EtcdMinion) raiseCorruptionAlarm(t *task.Task) {
+	if err := m.alarms.Raise(alarm.CORRUPT, t.TaskID.String()); err != nil {
+		log.Printf("Failed to raise corruption alarm for task %s: %s\n", t.TaskID, err)
+	}
+}
+
+// holdForAlarm reports whether a NOSPACE or CORRUPT alarm is
+// currently active for the minion, holding the task in queue and
+// logging rather than dispatching it to processTask, mirroring how
+// etcd itself rejects writes while an alarm is active
+func (m *v3EtcdMinion) holdForAlarm(t *task.Task) (bool, error) {
+	for _, at := range []alarm.Type{alarm.NOSPACE, alarm.CORRUPT} {
+		active, err := m.alarms.Active(at)
+		if err != nil {
+			return false, err
+		}
+
+		if active {
+			log.Printf("Holding task %s in queue: %s alarm is active\n", t.TaskID, at)
+			m.releaseTaskLease(t)
+			return true, m.requeueTask(t)
+		}
+	}
+
+	return false, nil
+}
+
+// releaseTaskLease revokes the lease a task was claimed under,
+// which also removes its key from the processing directory. It is
+// called both when a task reaches a terminal state and when it is
+// put back in queue to wait out an active alarm, so the deleted
+// key is marked in releasing first: otherwise the reaper would
+// mistake this deliberate removal for the minion having crashed
+// and reclaim the task a second time.
+func (m *v3EtcdMinion) releaseTaskLease(t *task.Task) {
+	v, ok := m.taskLeases.Load(t.TaskID.String())
+	if !ok {
+		return
+	}
+	m.taskLeases.Delete(t.TaskID.String())
+
+	tl := v.(*taskLease)
+	tl.cancel()
+
+	markReleasing(&m.releasing, t.TaskID.String())
+
+	if _, err := m.client.Revoke(context.Background(), tl.id); err != nil {
+		log.Printf("Failed to release processing lease for task %s: %s\n", t.TaskID, err)
+	}
+}
+
+// processTask processes a single task
+func (m *v3EtcdMinion) processTask(t *task.Task) error {
+	defer m.releaseTaskLease(t)
+
+	t.State = task.TaskStateProcessing
+	m.SaveTaskResult(t)
+
+	log.Printf("Processing task %s\n", t.TaskID)
+
+	ok, err := VerifyCatalogHash(t)
+	if err != nil || !ok {
+		log.Printf("Refusing to run corrupt task %s\n", t.TaskID)
+		t.State = task.TaskStateCorrupt
+		m.SaveTaskResult(t)
+		m.raiseCorruptionAlarm(t)
+
+		return fmt.Errorf("catalog hash mismatch for task %s", t.TaskID)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Loaded %d resources from catalog", t.Catalog.Len())
+	err = t.Catalog.Run(&buf)
+	t.TimeProcessed = time.Now().Unix()
+	t.Result = buf.String()
+
+	if err != nil {
+		log.Printf("Failed to process task %s: %s\n", t.TaskID, err)
+		t.State = task.TaskStateFailed
+	} else {
+		log.Printf("Finished processing task %s\n", t.TaskID)
+		t.State = task.TaskStateSuccess
+	}
+
+	m.SaveTaskResult(t)
+
+	return err
+}
+
+// V3UnmarshalTask unmarshals a task from an etcd v3 key-value pair
+func V3UnmarshalTask(kv *mvccpb.KeyValue) (*task.Task, error) {
+	t := new(task.Task)
+	err := json.Unmarshal(kv.Value, &t)
+
+	return t, err
+}
+
+// TaskListener watches etcd for new tasks and claims them
+// transactionally so that a task cannot be picked up by more than
+// one minion (or by checkQueue and the watch loop at the same time)
+func (m *v3EtcdMinion) TaskListener(c chan<- *task.Task) error {
+	log.Printf("Task listener is watching %s\n", m.queueDir)
+
+	rand.Seed(time.Now().UTC().UnixNano())
+	b := backoff.Backoff{
+		Min:    1 * time.Second,
+		Max:    10 * time.Minute,
+		Factor: 2.0,
+		Jitter: true,
+	}
+
+	watchCh := m.client.Watch(context.Background(), m.queueDir, clientv3.WithPrefix(), clientv3.WithPrevKV())
+
+	for resp := range watchCh {
+		if err := resp.Err(); err != nil {
+			duration := b.Duration()
+			log.Printf("%s, retrying in %s\n", err, duration)
+			time.Sleep(duration)
+			continue
+		}
+		b.Reset()
+
+		for _, ev := range resp.Events {
+			if ev.Type != clientv3.EventTypePut {
+				continue
+			}
+
+			txnResp, leaseID, err := m.claimTask(string(ev.Kv.Key), ev.Kv.ModRevision, ev.Kv.Value)
+			if err != nil {
+				log.Printf("Failed to claim task %s: %s\n", ev.Kv.Key, err)
+				continue
+			}
+
+			if !txnResp.Succeeded {
+				continue
+			}
+
+			log.Printf("Received task %s\n", ev.Kv.Key)
+			m.dispatch(ev.Kv.Value, leaseID)
+		}
+	}
+
+	return nil
+}
+
+// TaskRunner processes new tasks
+func (m *v3EtcdMinion) TaskRunner(c <-chan *task.Task) error {
+	log.Println("Starting task runner")
+
+	for {
+		select {
+		case <-m.done:
+			return nil
+		case t := <-c:
+			held, err := m.holdForAlarm(t)
+			if err != nil {
+				log.Printf("Failed to check alarms for task %s: %s\n", t.TaskID, err)
+			}
+			if held {
+				continue
+			}
+
+			t.State = task.TaskStateQueued
+			t.TimeReceived = time.Now().Unix()
+			m.SaveTaskResult(t)
+
+			if t.IsConcurrent {
+				go m.processTask(t)
+			} else {
+				m.processTask(t)
+			}
+		}
+	}
+}
+
+// SaveTaskResult stores the result of a task in etcd
+func (m *v3EtcdMinion) SaveTaskResult(t *task.Task) error {
+	taskKey := filepath.Join(m.logDir, t.TaskID.String())
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		log.Printf("Failed to serialize task %s: %s\n", t.TaskID, err)
+		return err
+	}
+
+	_, err = m.client.Put(context.Background(), taskKey, string(data))
+	if err != nil {
+		log.Printf("Failed to save task %s: %s\n", t.TaskID, err)
+	}
+
+	return err
+}
+
+// Serve starts the minion
+func (m *v3EtcdMinion) Serve() error {
+	resource.AlarmRaiser = func(t alarm.Type, member string) {
+		if err := m.alarms.Raise(t, member); err != nil {
+			log.Printf("Failed to raise %s alarm: %s\n", t, err)
+		}
+	}
+
+	if err := m.grantLease(); err != nil {
+		return err
+	}
+
+	if err := m.SetName(m.name); err != nil {
+		return err
+	}
+
+	if err := m.SetLastseen(time.Now().Unix()); err != nil {
+		return err
+	}
+
+	if err := m.requeueOrphans(); err != nil {
+		log.Printf("Failed to requeue orphaned tasks: %s\n", err)
+	}
+
+	// Start minion services
+	go m.classify()
+	go m.checkQueue()
+	go m.periodicRunner()
+	go m.reaper()
+	go m.TaskRunner(m.taskQueue)
+	go m.TaskListener(m.taskQueue)
+
+	log.Printf("Minion %s is ready to serve", m.ID())
+
+	return nil
+}
+
+// Stop shutdowns the minion and its services
+func (m *v3EtcdMinion) Stop() error {
+	log.Println("Minion is shutting down")
+
+	close(m.taskQueue)
+	close(m.done)
+
+	_, err := m.client.Revoke(context.Background(), m.leaseID)
+	if err != nil {
+		log.Printf("Failed to revoke lease: %s\n", err)
+	}
+
+	return m.client.Close()
+}
@@ -0,0 +1,106 @@
+package minion
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/dnaeon/gru/task"
+)
+
+func TestIsTerminalState(t *testing.T) {
+	terminal := []task.Task{
+		{State: task.TaskStateSuccess},
+		{State: task.TaskStateFailed},
+		{State: task.TaskStateCorrupt},
+	}
+	for _, tsk := range terminal {
+		tsk := tsk
+		if !isTerminalState(&tsk) {
+			t.Errorf("expected state %v to be terminal", tsk.State)
+		}
+	}
+
+	nonTerminal := []task.Task{
+		{State: task.TaskStateQueued},
+		{State: task.TaskStateProcessing},
+	}
+	for _, tsk := range nonTerminal {
+		tsk := tsk
+		if isTerminalState(&tsk) {
+			t.Errorf("expected state %v to not be terminal", tsk.State)
+		}
+	}
+}
+
+func TestPrepareReclaimRequeuesUnderMaxAttempts(t *testing.T) {
+	tsk := &task.Task{}
+
+	for i := 1; i < taskMaxAttempts; i++ {
+		if deadletter := prepareReclaim(tsk); deadletter {
+			t.Fatalf("attempt %d: expected requeue, got dead-letter", i)
+		}
+		if tsk.Attempts != i {
+			t.Fatalf("attempt %d: want Attempts=%d, got %d", i, i, tsk.Attempts)
+		}
+		if tsk.LastError == "" {
+			t.Fatalf("attempt %d: expected LastError to be set", i)
+		}
+	}
+}
+
+func TestPrepareReclaimDeadlettersAtMaxAttempts(t *testing.T) {
+	tsk := &task.Task{Attempts: taskMaxAttempts - 1}
+
+	if !prepareReclaim(tsk) {
+		t.Fatal("expected task to be dead-lettered once taskMaxAttempts is reached")
+	}
+	if tsk.Attempts != taskMaxAttempts {
+		t.Fatalf("want Attempts=%d, got %d", taskMaxAttempts, tsk.Attempts)
+	}
+}
+
+// TestConsumeReleasingRecognizesDeliberateRelease covers the bug
+// the reaper used to have: since a processing key's stored value is
+// the pre-run payload and never reflects the task's real outcome,
+// reclaimOrphan can't tell "releaseTaskLease deleted this on
+// purpose" apart from "the minion crashed and the lease expired"
+// just by looking at the deleted value. markReleasing/consumeReleasing
+// is the mechanism that lets it tell the two apart.
+func TestConsumeReleasingRecognizesDeliberateRelease(t *testing.T) {
+	var releasing sync.Map
+	taskID := "11111111-1111-1111-1111-111111111111"
+
+	markReleasing(&releasing, taskID)
+
+	if !consumeReleasing(&releasing, taskID) {
+		t.Fatal("expected a marked release to be recognized")
+	}
+}
+
+// TestConsumeReleasingConsumesMarkerOnce ensures a single
+// deliberate release is never mistaken for two, which would
+// otherwise let a completed task's marker mask a later crash on a
+// re-queued attempt with the same task ID.
+func TestConsumeReleasingConsumesMarkerOnce(t *testing.T) {
+	var releasing sync.Map
+	taskID := "22222222-2222-2222-2222-222222222222"
+
+	markReleasing(&releasing, taskID)
+	consumeReleasing(&releasing, taskID)
+
+	if consumeReleasing(&releasing, taskID) {
+		t.Fatal("expected the release marker to be consumed after the first check")
+	}
+}
+
+// TestConsumeReleasingReportsCrashOrphans ensures a processing key
+// that disappears without ever having been marked -- the crash
+// case -- is still reported so reclaimOrphan goes on to re-queue
+// or dead-letter it.
+func TestConsumeReleasingReportsCrashOrphans(t *testing.T) {
+	var releasing sync.Map
+
+	if consumeReleasing(&releasing, "33333333-3333-3333-3333-333333333333") {
+		t.Fatal("expected an unmarked task ID to not be reported as a deliberate release")
+	}
+}
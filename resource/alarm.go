@@ -0,0 +1,19 @@
+package resource
+
+import "github.com/dnaeon/gru/alarm"
+
+// AlarmRaiser is invoked by resources which detect an operational
+// condition that should stop a minion from silently failing future
+// tasks. A minion sets it to its own AlarmStore.Raise before running
+// a catalog, so resources never need to know whether they are
+// running under a minion, or how alarms are persisted.
+var AlarmRaiser func(t alarm.Type, member string)
+
+// raiseAlarm calls the registered AlarmRaiser, if any, so resources
+// don't have to nil-check it themselves, e.g. when evaluated outside
+// of a minion.
+func raiseAlarm(t alarm.Type, member string) {
+	if AlarmRaiser != nil {
+		AlarmRaiser(t, member)
+	}
+}
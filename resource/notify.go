@@ -0,0 +1,30 @@
+package resource
+
+// Reloader is implemented by resources which can apply a change in
+// place, such as a SystemdUnit's Reload. The catalog's dependency
+// graph calls Reload, when available, on resources reached via a
+// "notify" edge instead of the full Update used for regular Require
+// edges, so a config-change resource can prod a dependent service
+// without restarting it.
+type Reloader interface {
+	Reload() error
+}
+
+// NotifyTargets returns the names of the resources this one should
+// notify on change, i.e. the resources the catalog's graph builder
+// should wire up with a "notify" edge next to the regular "require"
+// edges built from Base.Require.
+func (s *SystemdUnit) NotifyTargets() []string {
+	return s.Notify
+}
+
+// ApplyNotify applies the effect of a notify edge to the target
+// resource: if it knows how to reload without a full restart it
+// does so, otherwise it falls back to a regular Update.
+func ApplyNotify(r Resource) error {
+	if reloader, ok := r.(Reloader); ok {
+		return reloader.Reload()
+	}
+
+	return r.Update()
+}
@@ -5,43 +5,67 @@ package resource
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
+
+	"github.com/dnaeon/gru/alarm"
 
 	"github.com/coreos/go-systemd/dbus"
 	"github.com/coreos/go-systemd/util"
 )
 
+// systemdUnreachableThreshold is the number of consecutive failures
+// to reach the systemd D-Bus API, across every SystemdUnit resource,
+// before a SYSTEMD_UNREACHABLE alarm is raised
+const systemdUnreachableThreshold = 3
+
+// systemdFailures counts consecutive dbus.New() failures in
+// unitProperty since its last success, shared by every SystemdUnit
+// resource since they all talk to the same local D-Bus
+var systemdFailures int32
+
 // ErrNoSystemd error is returned when the system is detected to
 // have no support for systemd.
 var ErrNoSystemd = errors.New("No systemd support found")
 
-// Service type is a resource which manages services on a
-// GNU/Linux system running with systemd.
+// SystemdUnit is the base type embedded by every resource which
+// manages a systemd unit. It knows how to start, stop, enable,
+// disable, mask, unmask and reload a unit; the concrete resource
+// types below only supply the unit name suffix for their taxonomy
+// (service, socket, timer, mount, path or target).
 //
-// Example:
-//   svc = resource.service.new("nginx")
-//   svc.state = "running"
-//   svc.enable = true
-type Service struct {
+// Notify lists other resources which should be reloaded, rather
+// than restarted, whenever this resource changes -- the dependency
+// graph turns each entry into a "notify" edge next to the regular
+// Require edges, and triggers the target's Reload instead of Update.
+type SystemdUnit struct {
 	Base
 
-	// If true then enable the service during boot-time
+	// If true then enable the unit during boot-time
 	Enable bool `luar:"enable"`
 
-	// Systemd unit name
+	// If true then mask the unit, preventing it from being
+	// started, either manually or as a dependency of another unit
+	Mask bool `luar:"mask"`
+
+	// Resources to notify-reload when this resource changes
+	Notify []string `luar:"notify"`
+
+	// Systemd unit name, including its type suffix
 	unit string `luar:"-"`
 }
 
-// NewService creates a new resource for managing services
-// using systemd on a GNU/Linux system
-func NewService(name string) (Resource, error) {
+// newSystemdUnit creates the shared SystemdUnit base for a given
+// resource type and unit suffix, e.g. ("nginx", "service", "service")
+// for a Service resource, or ("sync", "mount", "mount") for a Mount.
+func newSystemdUnit(name, resourceType, suffix string) (*SystemdUnit, error) {
 	if !util.IsRunningSystemd() {
 		return nil, ErrNoSystemd
 	}
 
-	s := &Service{
+	u := &SystemdUnit{
 		Base: Base{
 			Name:          name,
-			Type:          "service",
+			Type:          resourceType,
 			State:         "running",
 			Require:       make([]string, 0),
 			PresentStates: []string{"present", "running"},
@@ -49,18 +73,150 @@ func NewService(name string) (Resource, error) {
 			Concurrent:    true,
 		},
 		Enable: true,
-		unit:   fmt.Sprintf("%s.service", name),
+		unit:   fmt.Sprintf("%s.%s", name, suffix),
+	}
+
+	return u, nil
+}
+
+// Service type is a resource which manages services on a
+// GNU/Linux system running with systemd.
+//
+// Example:
+//   svc = resource.service.new("nginx")
+//   svc.state = "running"
+//   svc.enable = true
+type Service struct {
+	SystemdUnit
+}
+
+// NewService creates a new resource for managing services
+// using systemd on a GNU/Linux system
+func NewService(name string) (Resource, error) {
+	u, err := newSystemdUnit(name, "service", "service")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{SystemdUnit: *u}, nil
+}
+
+// Socket type is a resource which manages sockets on a
+// GNU/Linux system running with systemd.
+//
+// Example:
+//   sock = resource.socket.new("docker")
+//   sock.state = "running"
+type Socket struct {
+	SystemdUnit
+}
+
+// NewSocket creates a new resource for managing sockets
+// using systemd on a GNU/Linux system
+func NewSocket(name string) (Resource, error) {
+	u, err := newSystemdUnit(name, "socket", "socket")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Socket{SystemdUnit: *u}, nil
+}
+
+// Timer type is a resource which manages timers on a
+// GNU/Linux system running with systemd.
+//
+// Example:
+//   t = resource.timer.new("logrotate")
+//   t.state = "running"
+type Timer struct {
+	SystemdUnit
+}
+
+// NewTimer creates a new resource for managing timers
+// using systemd on a GNU/Linux system
+func NewTimer(name string) (Resource, error) {
+	u, err := newSystemdUnit(name, "timer", "timer")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Timer{SystemdUnit: *u}, nil
+}
+
+// Mount type is a resource which manages mount points on a
+// GNU/Linux system running with systemd.
+//
+// Example:
+//   m = resource.mount.new("mnt-data")
+//   m.state = "running"
+type Mount struct {
+	SystemdUnit
+}
+
+// NewMount creates a new resource for managing mount points
+// using systemd on a GNU/Linux system
+func NewMount(name string) (Resource, error) {
+	u, err := newSystemdUnit(name, "mount", "mount")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mount{SystemdUnit: *u}, nil
+}
+
+// Path type is a resource which manages path units on a
+// GNU/Linux system running with systemd.
+//
+// Example:
+//   p = resource.path.new("config-watch")
+//   p.state = "running"
+type Path struct {
+	SystemdUnit
+}
+
+// NewPath creates a new resource for managing path units
+// using systemd on a GNU/Linux system
+func NewPath(name string) (Resource, error) {
+	u, err := newSystemdUnit(name, "path", "path")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Path{SystemdUnit: *u}, nil
+}
+
+// Target type is a resource which manages targets on a
+// GNU/Linux system running with systemd.
+//
+// Example:
+//   t = resource.target.new("multi-user")
+//   t.state = "running"
+type Target struct {
+	SystemdUnit
+}
+
+// NewTarget creates a new resource for managing targets
+// using systemd on a GNU/Linux system
+func NewTarget(name string) (Resource, error) {
+	u, err := newSystemdUnit(name, "target", "target")
+	if err != nil {
+		return nil, err
 	}
 
-	return s, nil
+	return &Target{SystemdUnit: *u}, nil
 }
 
-// unitProperty retrieves the requested property for the service unit
-func (s *Service) unitProperty(name string) (*dbus.Property, error) {
+// unitProperty retrieves the requested property for the unit
+func (s *SystemdUnit) unitProperty(name string) (*dbus.Property, error) {
 	conn, err := dbus.New()
 	if err != nil {
+		if n := atomic.AddInt32(&systemdFailures, 1); n >= systemdUnreachableThreshold {
+			raiseAlarm(alarm.SYSTEMD_UNREACHABLE, s.Name)
+		}
+
 		return nil, err
 	}
+	atomic.StoreInt32(&systemdFailures, 0)
 	defer conn.Close()
 
 	property, err := conn.GetUnitProperty(s.unit, name)
@@ -69,7 +225,7 @@ func (s *Service) unitProperty(name string) (*dbus.Property, error) {
 }
 
 // unitIsEnabled checks if the unit is enabled or disabled
-func (s *Service) unitIsEnabled() (bool, error) {
+func (s *SystemdUnit) unitIsEnabled() (bool, error) {
 	unitState, err := s.unitProperty("UnitFileState")
 	if err != nil {
 		return false, err
@@ -88,15 +244,27 @@ func (s *Service) unitIsEnabled() (bool, error) {
 	}
 }
 
-// enableUnit enables the service unit during boot-time
-func (s *Service) enableUnit() error {
+// unitIsMasked checks if the unit is masked
+func (s *SystemdUnit) unitIsMasked() (bool, error) {
+	unitState, err := s.unitProperty("LoadState")
+	if err != nil {
+		return false, err
+	}
+
+	value := unitState.Value.Value().(string)
+
+	return value == "masked", nil
+}
+
+// enableUnit enables the unit during boot-time
+func (s *SystemdUnit) enableUnit() error {
 	conn, err := dbus.New()
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	s.Log("enabling service\n")
+	s.Log("enabling unit\n")
 
 	units := []string{s.unit}
 	_, changes, err := conn.EnableUnitFiles(units, false, false)
@@ -111,15 +279,15 @@ func (s *Service) enableUnit() error {
 	return nil
 }
 
-// disableUnit disables the service unit during boot-time
-func (s *Service) disableUnit() error {
+// disableUnit disables the unit during boot-time
+func (s *SystemdUnit) disableUnit() error {
 	conn, err := dbus.New()
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	s.Log("disabling service\n")
+	s.Log("disabling unit\n")
 
 	units := []string{s.unit}
 	changes, err := conn.DisableUnitFiles(units, false)
@@ -134,8 +302,70 @@ func (s *Service) disableUnit() error {
 	return nil
 }
 
-// setUnitState enables or disables the unit
-func (s *Service) setUnitState() error {
+// maskUnit masks the unit, preventing it from being started
+func (s *SystemdUnit) maskUnit() error {
+	conn, err := dbus.New()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.Log("masking unit\n")
+
+	units := []string{s.unit}
+	changes, err := conn.MaskUnitFiles(units, false, false)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		s.Log("%s %s\n", change.Type, change.Filename)
+	}
+
+	return nil
+}
+
+// unmaskUnit unmasks the unit
+func (s *SystemdUnit) unmaskUnit() error {
+	conn, err := dbus.New()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.Log("unmasking unit\n")
+
+	units := []string{s.unit}
+	changes, err := conn.UnmaskUnitFiles(units, false)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		s.Log("%s %s\n", change.Type, change.Filename)
+	}
+
+	return nil
+}
+
+// setUnitState enables/disables and masks/unmasks the unit to
+// match the resource's desired state
+func (s *SystemdUnit) setUnitState() error {
+	masked, err := s.unitIsMasked()
+	if err != nil {
+		return err
+	}
+
+	if s.Mask && !masked {
+		if err := s.maskUnit(); err != nil {
+			return err
+		}
+	} else if !s.Mask && masked {
+		if err := s.unmaskUnit(); err != nil {
+			return err
+		}
+	}
+
 	enabled, err := s.unitIsEnabled()
 	if err != nil {
 		return err
@@ -145,7 +375,7 @@ func (s *Service) setUnitState() error {
 		if err := s.enableUnit(); err != nil {
 			return err
 		}
-	} else {
+	} else if !s.Enable && enabled {
 		if err := s.disableUnit(); err != nil {
 			return err
 		}
@@ -155,7 +385,7 @@ func (s *Service) setUnitState() error {
 }
 
 // daemonReload instructs systemd to reload it's configuration
-func (s *Service) daemonReload() error {
+func (s *SystemdUnit) daemonReload() error {
 	conn, err := dbus.New()
 	if err != nil {
 		return err
@@ -166,21 +396,29 @@ func (s *Service) daemonReload() error {
 }
 
 // Evaluate evaluates the state of the resource
-func (s *Service) Evaluate() (State, error) {
+func (s *SystemdUnit) Evaluate() (State, error) {
 	state := State{
 		Current:  "unknown",
 		Want:     s.State,
 		Outdated: false,
 	}
 
+	loadState, err := s.unitProperty("LoadState")
+	if err != nil {
+		return state, err
+	}
+
+	if loadState.Value.Value().(string) == "not-found" {
+		state.Current = "absent"
+		return state, nil
+	}
+
 	// Check if the unit is started/stopped
 	activeState, err := s.unitProperty("ActiveState")
 	if err != nil {
 		return state, err
 	}
 
-	// TODO: Handle cases where the unit is not found
-
 	value := activeState.Value.Value().(string)
 	switch value {
 	case "active", "reloading", "activating":
@@ -194,22 +432,27 @@ func (s *Service) Evaluate() (State, error) {
 		return state, err
 	}
 
-	if s.Enable != enabled {
+	masked, err := s.unitIsMasked()
+	if err != nil {
+		return state, err
+	}
+
+	if s.Enable != enabled || s.Mask != masked {
 		state.Outdated = true
 	}
 
 	return state, nil
 }
 
-// Create starts the service unit
-func (s *Service) Create() error {
+// Create starts the unit
+func (s *SystemdUnit) Create() error {
 	conn, err := dbus.New()
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	s.Log("starting service\n")
+	s.Log("starting unit\n")
 
 	ch := make(chan string)
 	jobID, err := conn.StartUnit(s.unit, "replace", ch)
@@ -223,15 +466,15 @@ func (s *Service) Create() error {
 	return s.setUnitState()
 }
 
-// Delete stops the service unit
-func (s *Service) Delete() error {
+// Delete stops the unit
+func (s *SystemdUnit) Delete() error {
 	conn, err := dbus.New()
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	s.Log("stopping service\n")
+	s.Log("stopping unit\n")
 
 	ch := make(chan string)
 	jobID, err := conn.StopUnit(s.unit, "replace", ch)
@@ -245,17 +488,46 @@ func (s *Service) Delete() error {
 	return s.setUnitState()
 }
 
-// Update updates the service unit state
-func (s *Service) Update() error {
+// Update updates the unit state
+func (s *SystemdUnit) Update() error {
 	return s.setUnitState()
 }
 
+// Reload asks systemd to reload the unit's configuration in place,
+// without restarting it, so that resources which depend on this
+// unit via a "notify" edge can apply a config change cheaply
+func (s *SystemdUnit) Reload() error {
+	conn, err := dbus.New()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.Log("reloading unit\n")
+
+	ch := make(chan string)
+	jobID, err := conn.ReloadUnit(s.unit, "replace", ch)
+	if err != nil {
+		return err
+	}
+
+	result := <-ch
+	s.Log("systemd job id %d result: %s\n", jobID, result)
+
+	return nil
+}
+
 func init() {
-	item := RegistryItem{
-		Type:      "service",
-		Provider:  NewService,
-		Namespace: DefaultNamespace,
+	items := []RegistryItem{
+		{Type: "service", Provider: NewService, Namespace: DefaultNamespace},
+		{Type: "socket", Provider: NewSocket, Namespace: DefaultNamespace},
+		{Type: "timer", Provider: NewTimer, Namespace: DefaultNamespace},
+		{Type: "mount", Provider: NewMount, Namespace: DefaultNamespace},
+		{Type: "path", Provider: NewPath, Namespace: DefaultNamespace},
+		{Type: "target", Provider: NewTarget, Namespace: DefaultNamespace},
 	}
 
-	Register(item)
+	for _, item := range items {
+		Register(item)
+	}
 }